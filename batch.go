@@ -0,0 +1,132 @@
+package gosyntect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// BatchQuery is a batch of Query to highlight in a single call to
+// Client.HighlightBatch.
+type BatchQuery []Query
+
+// BatchResult is the result of highlighting a single Query within a
+// BatchQuery. Results preserve the order of the input BatchQuery, i.e.
+// BatchResult[i] corresponds to BatchQuery[i].
+type BatchResult struct {
+	Response *Response
+	Err      error
+}
+
+// batchCapability tracks whether syntectServer is known to support the
+// /batch endpoint, so repeat calls to HighlightBatch don't re-probe it.
+const (
+	batchCapabilityUnknown int32 = iota
+	batchCapabilitySupported
+	batchCapabilityUnsupported
+)
+
+// highlightBatchFanOutConcurrency bounds how many concurrent single-item
+// Highlight requests highlightBatchFanOut issues at once.
+const highlightBatchFanOutConcurrency = 8
+
+// HighlightBatch highlights many queries in one logical call. If
+// syntectServer supports the /batch endpoint, all queries are sent in a
+// single POST /batch request. Otherwise (detected once per Client and
+// cached for subsequent calls), it falls back to concurrency-limited
+// fan-out over Highlight so the same API works against older
+// syntect_server versions that predate /batch.
+func (c *Client) HighlightBatch(ctx context.Context, queries BatchQuery) ([]BatchResult, error) {
+	if atomic.LoadInt32(&c.batchCapability) != batchCapabilityUnsupported {
+		results, supported, err := c.highlightBatchRemote(ctx, queries)
+		if err != nil {
+			return nil, err
+		}
+		if supported {
+			atomic.StoreInt32(&c.batchCapability, batchCapabilitySupported)
+			return results, nil
+		}
+		atomic.StoreInt32(&c.batchCapability, batchCapabilityUnsupported)
+	}
+	return c.highlightBatchFanOut(ctx, queries), nil
+}
+
+// highlightBatchRemote POSTs queries to /batch. The second return value
+// reports whether syntectServer supports /batch at all (false on 404).
+func (c *Client) highlightBatchRemote(ctx context.Context, queries BatchQuery) ([]BatchResult, bool, error) {
+	jsonQuery, err := json.Marshal(queries)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "encoding batch query")
+	}
+	req, err := http.NewRequest("POST", c.url("/batch"), bytes.NewReader(jsonQuery))
+	if err != nil {
+		return nil, true, errors.Wrap(err, "building request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	for _, mw := range c.middleware {
+		req = mw(req)
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, true, errors.Wrap(err, fmt.Sprintf("making request to %s", c.url("/batch")))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, true, ErrRequestTooLarge
+	}
+
+	var items []response
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, true, errors.Wrap(err, fmt.Sprintf("decoding JSON response from %s", c.url("/batch")))
+	}
+	results := make([]BatchResult, len(items))
+	for i := range items {
+		item := &items[i]
+		if item.Error == "" {
+			results[i] = BatchResult{Response: item.toSuccessResponse()}
+			continue
+		}
+		var q *Query
+		if i < len(queries) {
+			q = &queries[i]
+		}
+		results[i] = BatchResult{Err: errors.Wrap(mapServerError(item, q), c.syntectServer)}
+	}
+	return results, true, nil
+}
+
+// highlightBatchFanOut highlights each query independently, with at most
+// highlightBatchFanOutConcurrency requests in flight at once, so one bad
+// file errors out only its own BatchResult.
+func (c *Client) highlightBatchFanOut(ctx context.Context, queries BatchQuery) []BatchResult {
+	results := make([]BatchResult, len(queries))
+	sem := make(chan struct{}, highlightBatchFanOutConcurrency)
+	var wg sync.WaitGroup
+	for i := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := c.Highlight(ctx, &queries[i])
+			results[i] = BatchResult{Response: resp, Err: err}
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,112 @@
+package gosyntect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHighlightBatch_Remote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		var qs []Query
+		if err := json.NewDecoder(r.Body).Decode(&qs); err != nil {
+			t.Fatal(err)
+		}
+		items := make([]response, len(qs))
+		for i, q := range qs {
+			switch q.Code {
+			case "bad":
+				items[i] = response{Error: "syntect panic", Code: "panic"}
+			case "huge":
+				items[i] = response{Error: "too large", Code: "too_large"}
+			default:
+				items[i] = response{Data: q.Code}
+			}
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL)
+	results, err := cl.HighlightBatch(context.Background(), BatchQuery{
+		{Filepath: "a.go", Code: "good"},
+		{Filepath: "b.go", Code: "bad"},
+		{Filepath: "c.go", Code: "huge"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].Response.Data != "good" {
+		t.Errorf("unexpected result[0]: %+v", results[0])
+	}
+	if results[1].Err == nil {
+		t.Fatalf("expected result[1] to carry an error")
+	}
+	var herr *HighlightError
+	if !errors.As(results[1].Err, &herr) {
+		t.Errorf("expected result[1].Err to wrap a *HighlightError, got %T: %v", results[1].Err, results[1].Err)
+	}
+	if results[2].Err == nil || !errors.Is(results[2].Err, ErrRequestTooLarge) {
+		t.Errorf("expected result[2].Err to wrap ErrRequestTooLarge, got %v", results[2].Err)
+	}
+}
+
+func TestHighlightBatch_FanOutFallback(t *testing.T) {
+	var batchRequests, highlightRequests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&batchRequests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&highlightRequests, 1)
+		var q Query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(response{Data: q.Code})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cl := New(srv.URL)
+	queries := BatchQuery{{Code: "a"}, {Code: "b"}, {Code: "c"}}
+
+	results, err := cl.HighlightBatch(context.Background(), queries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		if results[i].Err != nil || results[i].Response.Data != want {
+			t.Errorf("result[%d] = %+v, want Data=%q", i, results[i], want)
+		}
+	}
+	if got := atomic.LoadInt32(&highlightRequests); got != 3 {
+		t.Errorf("expected 3 fanned-out requests, got %d", got)
+	}
+	if got := atomic.LoadInt32(&batchRequests); got != 1 {
+		t.Errorf("expected /batch to be probed exactly once, got %d", got)
+	}
+
+	// A second call should skip probing /batch again, since the capability
+	// was already detected as unsupported and cached on the Client.
+	if _, err := cl.HighlightBatch(context.Background(), queries); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&batchRequests); got != 1 {
+		t.Errorf("expected /batch to still have been probed exactly once, got %d requests", got)
+	}
+}
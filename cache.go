@@ -0,0 +1,175 @@
+package gosyntect
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cache is implemented by types that can store and retrieve Highlight
+// responses, keyed by the content-addressed key computed by cacheKey. It is
+// safe to share a single Cache across many concurrent Client instances.
+type Cache interface {
+	// Get returns the cached response for key, if any.
+	Get(key string) (*Response, bool)
+
+	// Put stores resp under key.
+	Put(key string, resp *Response)
+}
+
+// WithCache enables response caching on the client using cache. Highlight
+// checks the cache before making a request, and populates it on success.
+// Errors (ErrInvalidTheme, ErrRequestTooLarge, ErrPanic, etc.) are never
+// cached.
+func WithCache(cache Cache) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// cacheKey computes a content-addressed cache key for q. Highlighting is a
+// pure function of the fields hashed here, so the key is stable across
+// processes and safe to share via a RemoteCache.
+func cacheKey(q *Query) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%t\x00%d\x00%d\x00%d\x00",
+		q.Theme, filepath.Ext(q.Filepath), q.Scopify, q.LineStart, q.LineEnd, q.Context)
+	io.WriteString(h, q.Code)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LRUCache is an in-memory Cache with a fixed byte budget, evicting the
+// least recently used entries once the budget is exceeded. The size of an
+// entry is estimated from the JSON-encoded size of its Response.
+type LRUCache struct {
+	maxBytes int
+
+	mu    sync.Mutex
+	bytes int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	resp  *Response
+	bytes int
+}
+
+// NewLRUCache returns an in-memory Cache that evicts least-recently-used
+// entries once the total estimated size of cached responses exceeds
+// maxBytes.
+func NewLRUCache(maxBytes int) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(key string) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruEntry).resp, true
+}
+
+// Put implements Cache.
+func (c *LRUCache) Put(key string, resp *Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	size := len(data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.bytes -= el.Value.(*lruEntry).bytes
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+	el := c.ll.PushFront(&lruEntry{key: key, resp: resp, bytes: size})
+	c.items[key] = el
+	c.bytes += size
+
+	for c.bytes > c.maxBytes {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		entry := oldest.Value.(*lruEntry)
+		delete(c.items, entry.key)
+		c.bytes -= entry.bytes
+	}
+}
+
+// RemoteCache is a skeleton Cache that speaks a small HTTP GET/PUT protocol,
+// analogous to buildkit's remote cache importer: Get issues a
+// "GET {addr}/{key}" and Put issues a "PUT {addr}/{key}" with a JSON-encoded
+// Response body. It exists so large deployments can share a cache (e.g.
+// backed by Redis or S3) across many gosyntect clients; callers are expected
+// to run the corresponding cache server themselves.
+type RemoteCache struct {
+	addr       string
+	httpClient *http.Client
+}
+
+// NewRemoteCache returns a RemoteCache that reads and writes entries against
+// the cache server at addr. If httpClient is nil, http.DefaultClient is
+// used.
+func NewRemoteCache(addr string, httpClient *http.Client) *RemoteCache {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &RemoteCache{addr: strings.TrimSuffix(addr, "/"), httpClient: httpClient}
+}
+
+// Get implements Cache.
+func (c *RemoteCache) Get(key string) (*Response, bool) {
+	resp, err := c.httpClient.Get(c.addr + "/" + key)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+	var r Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, false
+	}
+	return &r, true
+}
+
+// Put implements Cache.
+func (c *RemoteCache) Put(key string, resp *Response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest(http.MethodPut, c.addr+"/"+key, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return
+	}
+	httpResp.Body.Close()
+}
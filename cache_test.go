@@ -0,0 +1,129 @@
+package gosyntect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func mustMarshalResponse(r *Response) []byte {
+	data, err := json.Marshal(r)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestLRUCache_GetPutEvict(t *testing.T) {
+	entrySize := len(mustMarshalResponse(&Response{Data: strings.Repeat("x", 50)}))
+	c := NewLRUCache(entrySize*2 + 10) // room for roughly 2 entries
+
+	c.Put("k1", &Response{Data: strings.Repeat("x", 50)})
+	if _, ok := c.Get("k1"); !ok {
+		t.Fatalf("expected k1 to be cached immediately after Put")
+	}
+
+	// Push enough additional entries to exceed the byte budget many times
+	// over, so k1 (now the least recently used) gets evicted.
+	for i := 0; i < 10; i++ {
+		c.Put(fmt.Sprintf("k-other-%d", i), &Response{Data: strings.Repeat("y", 50)})
+	}
+	if _, ok := c.Get("k1"); ok {
+		t.Fatalf("expected k1 to have been evicted once the cache exceeded its byte budget")
+	}
+}
+
+func TestLRUCache_RecentlyUsedSurvives(t *testing.T) {
+	entrySize := len(mustMarshalResponse(&Response{Data: strings.Repeat("x", 50)}))
+	c := NewLRUCache(entrySize*2 + 10)
+
+	c.Put("k1", &Response{Data: strings.Repeat("x", 50)})
+	c.Put("k2", &Response{Data: strings.Repeat("y", 50)})
+
+	// Touch k1 so k2 becomes the least recently used.
+	if _, ok := c.Get("k1"); !ok {
+		t.Fatalf("expected k1 to be cached")
+	}
+	c.Put("k3", &Response{Data: strings.Repeat("z", 50)})
+
+	if _, ok := c.Get("k1"); !ok {
+		t.Errorf("expected recently-used k1 to survive eviction")
+	}
+	if _, ok := c.Get("k2"); ok {
+		t.Errorf("expected least-recently-used k2 to have been evicted")
+	}
+}
+
+func TestCacheKey_VariesByInputs(t *testing.T) {
+	base := &Query{Theme: "InspiredGitHub", Filepath: "foo.go", Code: "package main"}
+	key := cacheKey(base)
+
+	variants := []*Query{
+		{Theme: "Other", Filepath: "foo.go", Code: "package main"},
+		{Theme: "InspiredGitHub", Filepath: "foo.py", Code: "package main"},
+		{Theme: "InspiredGitHub", Filepath: "foo.go", Code: "package other"},
+		{Theme: "InspiredGitHub", Filepath: "foo.go", Code: "package main", Scopify: true},
+		{Theme: "InspiredGitHub", Filepath: "foo.go", Code: "package main", LineStart: 1, LineEnd: 1},
+	}
+	for i, v := range variants {
+		if cacheKey(v) == key {
+			t.Errorf("variant %d: expected a different cache key than the base query, got the same one", i)
+		}
+	}
+
+	same := cacheKey(&Query{Theme: "InspiredGitHub", Filepath: "foo.go", Code: "package main"})
+	if same != key {
+		t.Errorf("expected identical queries to produce the same cache key")
+	}
+}
+
+func TestClient_WithCache_AvoidsRepeatRequests(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(response{Data: "<span>hi</span>"})
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL, WithCache(NewLRUCache(1<<20)))
+	q := &Query{Theme: "t", Filepath: "a.go", Code: "hi"}
+
+	for i := 0; i < 3; i++ {
+		resp, err := cl.Highlight(context.Background(), q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data != "<span>hi</span>" {
+			t.Fatalf("unexpected data: %q", resp.Data)
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Fatalf("expected exactly 1 request to reach the server, got %d", got)
+	}
+}
+
+func TestClient_WithCache_NeverCachesErrors(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		json.NewEncoder(w).Encode(response{Error: "syntect panic", Code: "panic"})
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL, WithCache(NewLRUCache(1<<20)))
+	q := &Query{Theme: "t", Filepath: "a.go", Code: "hi"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cl.Highlight(context.Background(), q); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected errors to never be cached (2 requests), got %d", got)
+	}
+}
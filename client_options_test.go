@@ -0,0 +1,164 @@
+package gosyntect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type countingRoundTripper struct {
+	underlying http.RoundTripper
+	count      int
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.count++
+	return c.underlying.RoundTrip(req)
+}
+
+func TestClient_WithRequestMiddleware_OrderAndMutation(t *testing.T) {
+	var gotHeaders []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeaders = append(gotHeaders, r.Header.Get("X-Order"))
+		json.NewEncoder(w).Encode(response{Data: "ok"})
+	}))
+	defer srv.Close()
+
+	var order []string
+	mw := func(tag string) func(*http.Request) *http.Request {
+		return func(req *http.Request) *http.Request {
+			order = append(order, tag)
+			req.Header.Set("X-Order", req.Header.Get("X-Order")+tag)
+			return req
+		}
+	}
+
+	cl := New(srv.URL,
+		WithRequestMiddleware(mw("a")),
+		WithRequestMiddleware(mw("b")),
+		WithRequestMiddleware(mw("c")),
+	)
+	if _, err := cl.Highlight(context.Background(), &Query{Code: "x"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b", "c"}; !equalStrings(order, want) {
+		t.Errorf("middleware ran in order %v, want %v", order, want)
+	}
+	if len(gotHeaders) != 1 || gotHeaders[0] != "abc" {
+		t.Errorf("server saw X-Order headers %v, want a single request with %q", gotHeaders, "abc")
+	}
+}
+
+func TestClient_WithUserAgent(t *testing.T) {
+	var gotUA string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA = r.Header.Get("User-Agent")
+		json.NewEncoder(w).Encode(response{Data: "ok"})
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL, WithUserAgent("gosyntect-test/1.0"))
+	if _, err := cl.Highlight(context.Background(), &Query{Code: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if gotUA != "gosyntect-test/1.0" {
+		t.Errorf("User-Agent = %q, want %q", gotUA, "gosyntect-test/1.0")
+	}
+}
+
+func TestClient_WithHTTPClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(response{Data: "ok"})
+	}))
+	defer srv.Close()
+
+	rt := &countingRoundTripper{underlying: http.DefaultTransport}
+	cl := New(srv.URL, WithHTTPClient(&http.Client{Transport: rt}))
+	if _, err := cl.Highlight(context.Background(), &Query{Code: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if rt.count != 1 {
+		t.Errorf("expected the custom http.Client's RoundTripper to be used exactly once, got %d", rt.count)
+	}
+}
+
+func TestClient_WithUserAgentAndMiddleware_AppliedToBatch(t *testing.T) {
+	var gotUA, gotOrder string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/batch" {
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+		gotUA = r.Header.Get("User-Agent")
+		gotOrder = r.Header.Get("X-Order")
+		var qs []Query
+		if err := json.NewDecoder(r.Body).Decode(&qs); err != nil {
+			t.Fatal(err)
+		}
+		items := make([]response, len(qs))
+		for i, q := range qs {
+			items[i] = response{Data: q.Code}
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL,
+		WithUserAgent("gosyntect-test/1.0"),
+		WithRequestMiddleware(func(req *http.Request) *http.Request {
+			req.Header.Set("X-Order", req.Header.Get("X-Order")+"a")
+			return req
+		}),
+		WithRequestMiddleware(func(req *http.Request) *http.Request {
+			req.Header.Set("X-Order", req.Header.Get("X-Order")+"b")
+			return req
+		}),
+	)
+	if _, err := cl.HighlightBatch(context.Background(), BatchQuery{{Code: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if gotUA != "gosyntect-test/1.0" {
+		t.Errorf("batch User-Agent = %q, want %q", gotUA, "gosyntect-test/1.0")
+	}
+	if gotOrder != "ab" {
+		t.Errorf("batch middleware order = %q, want %q", gotOrder, "ab")
+	}
+}
+
+func TestClient_WithHTTPClient_AppliedToBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var qs []Query
+		if err := json.NewDecoder(r.Body).Decode(&qs); err != nil {
+			t.Fatal(err)
+		}
+		items := make([]response, len(qs))
+		for i, q := range qs {
+			items[i] = response{Data: q.Code}
+		}
+		json.NewEncoder(w).Encode(items)
+	}))
+	defer srv.Close()
+
+	rt := &countingRoundTripper{underlying: http.DefaultTransport}
+	cl := New(srv.URL, WithHTTPClient(&http.Client{Transport: rt}))
+	if _, err := cl.HighlightBatch(context.Background(), BatchQuery{{Code: "x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if rt.count != 1 {
+		t.Errorf("expected the custom http.Client's RoundTripper to be used exactly once for the batch request, got %d", rt.count)
+	}
+}
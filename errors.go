@@ -0,0 +1,95 @@
+package gosyntect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorContext carries diagnostic information about where in a Query a
+// HighlightError occurred, so that opaque sentinel errors like ErrPanic are
+// actionable in production log streams.
+type ErrorContext struct {
+	Filepath     string
+	Line         int
+	Column       int
+	Snippet      string
+	GrammarScope string
+}
+
+// HighlightError wraps a sentinel error (currently only ErrPanic) with an
+// ErrorContext describing where in Query.Code the failure occurred. Recover
+// one from an error returned by Client.Highlight with errors.As:
+//
+//	var herr *gosyntect.HighlightError
+//	if errors.As(err, &herr) {
+//		log.Print(herr.Format())
+//	}
+type HighlightError struct {
+	Err     error
+	Context ErrorContext
+}
+
+// Error implements error.
+func (e *HighlightError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows errors.Is/errors.As to see through to Err (e.g. ErrPanic).
+func (e *HighlightError) Unwrap() error {
+	return e.Err
+}
+
+// Format renders e as a Hugo-style "file:line:col: message" block with a
+// code snippet, suitable for dropping straight into editor problem panes
+// and CI logs:
+//
+//	foo.py:42:1: syntect panic while highlighting
+//	  def broken(:
+func (e *HighlightError) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:%d: %s\n", e.Context.Filepath, e.Context.Line, e.Context.Column, e.Err.Error())
+	for _, line := range strings.Split(e.Context.Snippet, "\n") {
+		if line == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s\n", line)
+	}
+	return b.String()
+}
+
+// buildErrorContext builds an ErrorContext for a failed response r against
+// the query q that produced it. If the server reported a line/column/scope
+// (see response.Line, response.Column, response.GrammarScope), those are
+// used verbatim. Otherwise a best-effort context is synthesised from the
+// last few lines of q.Code plus its total length, since that's still more
+// actionable than nothing.
+func buildErrorContext(r *response, q *Query) ErrorContext {
+	ctx := ErrorContext{Line: r.Line, Column: r.Column, GrammarScope: r.GrammarScope}
+	if q == nil {
+		return ctx
+	}
+	ctx.Filepath = q.Filepath
+
+	lines := splitLinesKeepEnds(q.Code)
+	if ctx.Line > 0 {
+		if i := ctx.Line - 1; i >= 0 && i < len(lines) {
+			ctx.Snippet = strings.TrimRight(lines[i], "\n")
+		}
+		return ctx
+	}
+
+	// The server didn't tell us where it panicked; fall back to the tail of
+	// the input.
+	const tailLines = 3
+	start := len(lines) - tailLines
+	if start < 0 {
+		start = 0
+	}
+	ctx.Line = len(lines)
+	tail := strings.Join(lines[start:], "")
+	if !strings.HasSuffix(tail, "\n") {
+		tail += "\n"
+	}
+	ctx.Snippet = fmt.Sprintf("%s[%d bytes total]", tail, len(q.Code))
+	return ctx
+}
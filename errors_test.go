@@ -0,0 +1,78 @@
+package gosyntect
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildErrorContext_ServerProvided(t *testing.T) {
+	q := &Query{Filepath: "foo.py", Code: "a\nb\nc\n"}
+	r := &response{Line: 2, Column: 3, GrammarScope: "source.python"}
+
+	ctx := buildErrorContext(r, q)
+	if ctx.Filepath != "foo.py" || ctx.Line != 2 || ctx.Column != 3 || ctx.GrammarScope != "source.python" {
+		t.Errorf("unexpected context: %+v", ctx)
+	}
+	if ctx.Snippet != "b" {
+		t.Errorf("Snippet = %q, want %q", ctx.Snippet, "b")
+	}
+}
+
+func TestBuildErrorContext_SynthesizedTail(t *testing.T) {
+	// Deliberately no trailing newline, the common case for a code
+	// fragment rather than a whole file.
+	q := &Query{Filepath: "foo.py", Code: "line1\nline2\nline3\nline4"}
+	r := &response{}
+
+	ctx := buildErrorContext(r, q)
+	if ctx.Line != 4 {
+		t.Errorf("Line = %d, want 4", ctx.Line)
+	}
+	if strings.Contains(ctx.Snippet, "line1") {
+		t.Errorf("Snippet = %q, want only the last 3 lines (no line1)", ctx.Snippet)
+	}
+	if !strings.Contains(ctx.Snippet, "line2") || !strings.Contains(ctx.Snippet, "line4") {
+		t.Errorf("Snippet = %q, want it to contain the last 3 lines", ctx.Snippet)
+	}
+	wantNote := fmt.Sprintf("[%d bytes total]", len(q.Code))
+	if !strings.Contains(ctx.Snippet, wantNote) {
+		t.Errorf("Snippet = %q, want it to contain %q", ctx.Snippet, wantNote)
+	}
+	// The byte-count note must not be glued onto the last content line.
+	if strings.Contains(ctx.Snippet, "line4"+wantNote) {
+		t.Errorf("Snippet = %q, want the byte-count note on its own line, not appended to line4", ctx.Snippet)
+	}
+}
+
+func TestHighlightError_FormatSeparatesByteCountNote(t *testing.T) {
+	q := &Query{Filepath: "foo.py", Code: "line1\nline2\nline3\nline4"}
+	herr := &HighlightError{Err: ErrPanic, Context: buildErrorContext(&response{}, q)}
+
+	formatted := herr.Format()
+	for _, line := range strings.Split(strings.TrimRight(formatted, "\n"), "\n") {
+		if strings.Contains(line, "line4") && strings.Contains(line, "bytes total") {
+			t.Errorf("expected the byte-count note on its own line, got it merged with the snippet line: %q", line)
+		}
+	}
+	if !strings.Contains(formatted, "bytes total") {
+		t.Errorf("expected formatted output to include the byte-count note, got %q", formatted)
+	}
+	if !strings.HasPrefix(formatted, "foo.py:4:0: ") {
+		t.Errorf("formatted = %q, want it to start with the file:line:col prefix", formatted)
+	}
+}
+
+func TestHighlightError_UnwrapsToSentinel(t *testing.T) {
+	herr := &HighlightError{Err: ErrPanic, Context: ErrorContext{Filepath: "foo.py"}}
+
+	var target error = herr
+	if !errors.Is(target, ErrPanic) {
+		t.Errorf("expected errors.Is(herr, ErrPanic) to be true")
+	}
+	var got *HighlightError
+	if !errors.As(target, &got) || got != herr {
+		t.Errorf("expected errors.As to recover the original *HighlightError")
+	}
+}
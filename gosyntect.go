@@ -37,6 +37,25 @@ type Query struct {
 
 	// Code is the literal code to highlight.
 	Code string `json:"code"`
+
+	// LineStart and LineEnd restrict highlighting to a 1-indexed, inclusive
+	// line range within Code (e.g. just the window a code search hit, blame
+	// view, or stack frame needs) instead of the whole file. Leave both
+	// zero to highlight all of Code, as before.
+	LineStart int `json:"-"`
+	LineEnd   int `json:"-"`
+
+	// Context is the number of extra lines of leading context to include
+	// ahead of LineStart when slicing Code, so that grammars which carry
+	// state across lines (e.g. Python, YAML) have enough history to
+	// re-enter the right state by the time LineStart is reached. Ignored
+	// unless LineStart is set.
+	Context int `json:"-"`
+
+	// LineOffset is set by Highlight to tell syntect_server how many
+	// leading lines were sliced off the front of Code, when LineStart is
+	// set. Callers should not set this directly.
+	LineOffset int `json:"line_offset,omitempty"`
 }
 
 // Response represents a response to a code highlighting query.
@@ -99,8 +118,34 @@ type response struct {
 	ScopifiedRegions    []ScopifiedRegion `json:"scopified_regions"`
 
 	// Error response fields.
-	Error string
-	Code  string
+	Error        string
+	Code         string
+	Line         int    `json:"line,omitempty"`
+	Column       int    `json:"column,omitempty"`
+	GrammarScope string `json:"scope,omitempty"`
+}
+
+// mapServerError maps a response's error/code fields (shared by Highlight
+// and HighlightBatch) to a sentinel or descriptive error. q is the query
+// that produced r, used to build an ErrorContext for panics.
+func mapServerError(r *response, q *Query) error {
+	switch r.Code {
+	case "invalid_theme":
+		return ErrInvalidTheme
+	case "resource_not_found":
+		// resource_not_found is returned in the event of a 404, indicating a bug
+		// in gosyntect.
+		return errors.New("gosyntect internal error: resource_not_found")
+	case "too_large":
+		// too_large is how an oversized item is reported inside a /batch
+		// response; a single-query request instead rejects the whole
+		// request with a 400 (see the StatusBadRequest check in Highlight).
+		return ErrRequestTooLarge
+	case "panic":
+		return &HighlightError{Err: ErrPanic, Context: buildErrorContext(r, q)}
+	default:
+		return fmt.Errorf("unknown error=%q code=%q", r.Error, r.Code)
+	}
 }
 
 func (r *response) toSuccessResponse() *Response {
@@ -120,12 +165,94 @@ func (r *response) toSuccessResponse() *Response {
 // Client represents a client connection to a syntect_server.
 type Client struct {
 	syntectServer string
+
+	httpClient *http.Client
+	middleware []func(*http.Request) *http.Request
+	userAgent  string
+	cache      Cache
+
+	// chunkFallback, if set via WithChunkedFallback, makes Highlight retry a
+	// full-file request that was rejected with ErrRequestTooLarge as a
+	// series of overlapping line-range windows, stitched back together.
+	chunkFallback *chunkFallbackConfig
+
+	// batchCapability records whether syntectServer supports the /batch
+	// endpoint, once detected by HighlightBatch. See batchCapabilityUnknown
+	// and friends in batch.go.
+	batchCapability int32
+}
+
+// ClientOption configures a Client as constructed by New.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http.Client used to perform requests, overriding
+// the default client (which wraps http.DefaultTransport in OpenTracing
+// instrumentation). Use this to supply a pooled client, custom timeouts, or
+// a client that already handles auth/tracing on its own.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRoundTripper sets the http.RoundTripper used by the client's default
+// http.Client, wrapped in the same OpenTracing instrumentation New uses by
+// default. Use this to put the client behind an auth proxy, add retry/
+// backoff or a circuit breaker, without losing tracing support.
+func WithRoundTripper(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.httpClient = &http.Client{Transport: &nethttp.Transport{RoundTripper: rt}}
+	}
+}
+
+// WithRequestMiddleware registers a middleware that is invoked, in the order
+// added, on every outgoing request before it is sent (after the request body
+// and Content-Type header are set, before tracing instrumentation is
+// attached). A middleware may mutate and return the same *http.Request, or
+// return a replacement. Use this e.g. to add auth or tenant headers.
+func WithRequestMiddleware(fn func(*http.Request) *http.Request) ClientOption {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, fn)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
 }
 
 // Highlight performs a query to highlight some code.
 func (c *Client) Highlight(ctx context.Context, q *Query) (*Response, error) {
+	// Check the cache before doing any work. Only successful responses are
+	// ever cached, see the bottom of this method.
+	var key string
+	if c.cache != nil {
+		key = cacheKey(q)
+		if resp, ok := c.cache.Get(key); ok {
+			return resp, nil
+		}
+	}
+
+	// If a line range was requested, slice Code down to just the window the
+	// caller needs (plus leading context), and remember the byte offset of
+	// that slice so we can translate ScopifiedRegion.Offset back into the
+	// original file's coordinates below.
+	sendQuery := q
+	var byteOffset, lineOffset int
+	if q.LineStart > 0 {
+		sliced, offset, lo := sliceForLineRange(q)
+		windowed := *q
+		windowed.Code = sliced
+		windowed.LineOffset = lo
+		sendQuery = &windowed
+		byteOffset = offset
+		lineOffset = lo
+	}
+
 	// Build the request.
-	jsonQuery, err := json.Marshal(q)
+	jsonQuery, err := json.Marshal(sendQuery)
 	if err != nil {
 		return nil, errors.Wrap(err, "encoding query")
 	}
@@ -134,6 +261,14 @@ func (c *Client) Highlight(ctx context.Context, q *Query) (*Response, error) {
 		return nil, errors.Wrap(err, "building request")
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	// Run the request through any configured middleware.
+	for _, mw := range c.middleware {
+		req = mw(req)
+	}
 
 	// Add tracing to the request.
 	req = req.WithContext(ctx)
@@ -141,22 +276,37 @@ func (c *Client) Highlight(ctx context.Context, q *Query) (*Response, error) {
 		nethttp.OperationName("Highlight"),
 		nethttp.ClientTrace(false))
 	defer ht.Finish()
-	client := &http.Client{Transport: &nethttp.Transport{}}
 
 	// Perform the request.
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("making request to %s", c.url("/")))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusBadRequest {
+		if c.chunkFallback != nil && q.LineStart == 0 {
+			chunked, err := c.highlightChunked(ctx, q)
+			if err != nil {
+				return nil, err
+			}
+			if c.cache != nil {
+				c.cache.Put(key, chunked)
+			}
+			return chunked, nil
+		}
 		return nil, ErrRequestTooLarge
 	}
 
-	// Can only call ht.Span() after the request has been exected, so add our span tags in now.
-	ht.Span().SetTag("Filepath", q.Filepath)
-	ht.Span().SetTag("Theme", q.Theme)
+	// Can only call ht.Span() after the request has been exected, so add our
+	// span tags in now. ht.Span() is nil if c.httpClient's Transport isn't a
+	// nethttp.Transport (e.g. a client supplied via WithHTTPClient that
+	// handles its own tracing), since the span is only started from within
+	// nethttp.Transport.RoundTrip.
+	if span := ht.Span(); span != nil {
+		span.SetTag("Filepath", q.Filepath)
+		span.SetTag("Theme", q.Theme)
+	}
 
 	// Decode the response.
 	var r response
@@ -164,22 +314,22 @@ func (c *Client) Highlight(ctx context.Context, q *Query) (*Response, error) {
 		return nil, errors.Wrap(err, fmt.Sprintf("decoding JSON response from %s", c.url("/")))
 	}
 	if r.Error != "" {
-		var err error
-		switch r.Code {
-		case "invalid_theme":
-			err = ErrInvalidTheme
-		case "resource_not_found":
-			// resource_not_found is returned in the event of a 404, indicating a bug
-			// in gosyntect.
-			err = errors.New("gosyntect internal error: resource_not_found")
-		case "panic":
-			err = ErrPanic
-		default:
-			err = fmt.Errorf("unknown error=%q code=%q", r.Error, r.Code)
+		err := mapServerError(&r, sendQuery)
+		if herr, ok := err.(*HighlightError); ok && lineOffset > 0 {
+			herr.Context.Line += lineOffset
 		}
 		return nil, errors.Wrap(err, c.syntectServer)
 	}
-	return r.toSuccessResponse(), nil
+	successResponse := r.toSuccessResponse()
+	if byteOffset > 0 {
+		for i := range successResponse.ScopifiedRegions {
+			successResponse.ScopifiedRegions[i].Offset += byteOffset
+		}
+	}
+	if c.cache != nil {
+		c.cache.Put(key, successResponse)
+	}
+	return successResponse, nil
 }
 
 func (c *Client) url(path string) string {
@@ -187,8 +337,13 @@ func (c *Client) url(path string) string {
 }
 
 // New returns a client connection to a syntect_server.
-func New(syntectServer string) *Client {
-	return &Client{
+func New(syntectServer string, options ...ClientOption) *Client {
+	c := &Client{
 		syntectServer: strings.TrimSuffix(syntectServer, "/"),
+		httpClient:    &http.Client{Transport: &nethttp.Transport{}},
+	}
+	for _, opt := range options {
+		opt(c)
 	}
+	return c
 }
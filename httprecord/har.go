@@ -0,0 +1,110 @@
+package httprecord
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// The types below are a subset of the HAR 1.2 format sufficient to round-
+// trip the request/response pairs gosyntect.Client makes (JSON bodies, no
+// cookies, redirects or timing data). Fixtures written here remain
+// inspectable and interoperable with browser devtools network exports.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/ for the full spec.
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	Request  harRequest  `json:"request"`
+	Response harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// loadHAR reads and parses the HAR file at path, returning its entries.
+func loadHAR(path string) ([]harEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f harFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.Log.Entries, nil
+}
+
+// appendHAR appends entry to the HAR file at path, creating it (with a
+// fresh log section) if it does not already exist.
+func appendHAR(path string, entry harEntry) error {
+	var f harFile
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &f); err != nil {
+			return err
+		}
+	}
+	if f.Log.Version == "" {
+		f.Log.Version = "1.2"
+		f.Log.Creator = harCreator{Name: "gosyntect/httprecord", Version: "1.0"}
+	}
+	f.Log.Entries = append(f.Log.Entries, entry)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func headerToHAR(h map[string][]string) []harNameValue {
+	var out []harNameValue
+	for name, values := range h {
+		for _, v := range values {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
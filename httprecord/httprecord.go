@@ -0,0 +1,236 @@
+// Package httprecord implements an http.RoundTripper that records real HTTP
+// exchanges to, or replays them from, a HAR 1.2 fixture file, so tests that
+// use gosyntect.Client don't need a real syntect_server running. Wire it
+// through gosyntect.Client the same way as any other transport:
+//
+//	cl := gosyntect.New(srv, gosyntect.WithRoundTripper(
+//		httprecord.New("testdata/highlight.har", httprecord.ModeReplay)))
+package httprecord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mode controls how a Transport behaves.
+type Mode int
+
+const (
+	// ModeRecord proxies every request to the real server and appends the
+	// request/response pair to the fixture file.
+	ModeRecord Mode = iota
+
+	// ModeReplay serves responses from the fixture file and returns an
+	// error on a miss, instead of making a real request.
+	ModeReplay
+
+	// ModePassthrough proxies every request to the real server without
+	// recording anything, so recording can be toggled on and off without
+	// changing call sites.
+	ModePassthrough
+)
+
+// Transport implements http.RoundTripper, recording to or replaying from a
+// HAR 1.2 fixture file depending on Mode.
+type Transport struct {
+	path       string
+	mode       Mode
+	underlying http.RoundTripper
+
+	mu      sync.Mutex
+	entries []harEntry
+	used    map[int]bool
+}
+
+// Option configures a Transport as constructed by New.
+type Option func(*Transport)
+
+// WithUnderlyingTransport overrides the http.RoundTripper used to perform
+// real requests in ModeRecord and ModePassthrough. Defaults to
+// http.DefaultTransport.
+func WithUnderlyingTransport(rt http.RoundTripper) Option {
+	return func(t *Transport) {
+		t.underlying = rt
+	}
+}
+
+// New returns a Transport in the given mode, backed by the HAR fixture at
+// path. In ModeReplay the fixture is loaded immediately; New panics if it
+// cannot be read or parsed, since a broken checked-in fixture is a
+// programmer error rather than something callers should need to handle.
+func New(path string, mode Mode, opts ...Option) *Transport {
+	t := &Transport{
+		path:       path,
+		mode:       mode,
+		underlying: http.DefaultTransport,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if mode == ModeReplay {
+		entries, err := loadHAR(path)
+		if err != nil {
+			panic(fmt.Sprintf("httprecord: loading %s: %v", path, err))
+		}
+		t.entries = entries
+		t.used = map[int]bool{}
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch t.mode {
+	case ModeReplay:
+		return t.replay(req)
+	case ModePassthrough:
+		return t.underlying.RoundTrip(req)
+	default:
+		return t.record(req)
+	}
+}
+
+func (t *Transport) record(req *http.Request) (*http.Response, error) {
+	var bodyBytes []byte
+	if req.Body != nil {
+		var err error
+		bodyBytes, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "httprecord: reading request body")
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
+	resp, err := t.underlying.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "httprecord: reading response body")
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	entry := harEntry{
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Headers:     headerToHAR(req.Header),
+		},
+		Response: harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     headerToHAR(resp.Header),
+			Content: harContent{
+				Size:     len(respBody),
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     string(respBody),
+			},
+		},
+	}
+	if len(bodyBytes) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(bodyBytes),
+		}
+	}
+
+	t.mu.Lock()
+	saveErr := appendHAR(t.path, entry)
+	t.mu.Unlock()
+	if saveErr != nil {
+		return nil, errors.Wrap(saveErr, fmt.Sprintf("httprecord: recording to %s", t.path))
+	}
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request) (*http.Response, error) {
+	method, path, body, err := requestKey(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "httprecord: canonicalising request")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, entry := range t.entries {
+		if t.used[i] {
+			continue
+		}
+		entryURL, err := url.Parse(entry.Request.URL)
+		if err != nil {
+			continue
+		}
+		entryBody := ""
+		if entry.Request.PostData != nil {
+			entryBody = canonicalizeBody([]byte(entry.Request.PostData.Text))
+		}
+		if entry.Request.Method == method && entryURL.Path == path && entryBody == body {
+			t.used[i] = true
+			return harEntryToResponse(req, entry), nil
+		}
+	}
+	return nil, errors.Errorf("httprecord: no replay match for %s %s in %s", method, path, t.path)
+}
+
+// requestKey extracts the (method, path, canonicalised body) tuple used to
+// match a request against recorded HAR entries. It consumes and replaces
+// req.Body so the caller can still read it afterwards.
+func requestKey(req *http.Request) (method, path, canonicalBody string, err error) {
+	var bodyBytes []byte
+	if req.Body != nil {
+		bodyBytes, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", "", "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+	}
+	return req.Method, req.URL.Path, canonicalizeBody(bodyBytes), nil
+}
+
+// canonicalizeBody normalises a JSON request body (sorted keys, no
+// incidental whitespace) so that small formatting drift in the body doesn't
+// invalidate a fixture. Non-JSON or malformed bodies are compared verbatim.
+func canonicalizeBody(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	canon, err := json.Marshal(v)
+	if err != nil {
+		return string(data)
+	}
+	return string(canon)
+}
+
+func harEntryToResponse(req *http.Request, entry harEntry) *http.Response {
+	header := http.Header{}
+	for _, nv := range entry.Response.Headers {
+		header.Add(nv.Name, nv.Value)
+	}
+	body := []byte(entry.Response.Content.Text)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,73 @@
+package httprecord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/sourcegraph/gosyntect"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	var requests int
+	fake := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"Data": "<span>hi</span>"})
+	}))
+
+	harPath := filepath.Join(t.TempDir(), "highlight.har")
+
+	// Record a real exchange against the fake server.
+	recordCl := gosyntect.New(fake.URL, gosyntect.WithRoundTripper(New(harPath, ModeRecord)))
+	resp, err := recordCl.Highlight(context.Background(), &gosyntect.Query{Filepath: "a.go", Theme: "t", Code: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data != "<span>hi</span>" {
+		t.Fatalf("unexpected recorded Data: %q", resp.Data)
+	}
+	if requests != 1 {
+		t.Fatalf("expected exactly 1 request to the fake server, got %d", requests)
+	}
+
+	// Shut the fake server down so replay can't possibly cheat by hitting
+	// the network.
+	fake.Close()
+
+	replayCl := gosyntect.New(fake.URL, gosyntect.WithRoundTripper(New(harPath, ModeReplay)))
+	resp, err = replayCl.Highlight(context.Background(), &gosyntect.Query{Filepath: "a.go", Theme: "t", Code: "hi"})
+	if err != nil {
+		t.Fatalf("replaying recorded request: %v", err)
+	}
+	if resp.Data != "<span>hi</span>" {
+		t.Fatalf("unexpected replayed Data: %q", resp.Data)
+	}
+}
+
+func TestReplay_NoMatchFails(t *testing.T) {
+	harPath := filepath.Join(t.TempDir(), "empty.har")
+	entry := harEntry{
+		Request:  harRequest{Method: "POST", URL: "http://example.com/"},
+		Response: harResponse{Status: 200},
+	}
+	if err := appendHAR(harPath, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	cl := gosyntect.New("http://example.com", gosyntect.WithRoundTripper(New(harPath, ModeReplay)))
+	if _, err := cl.Highlight(context.Background(), &gosyntect.Query{Code: "no such recorded request"}); err == nil {
+		t.Fatal("expected an error for a request with no matching HAR entry")
+	}
+}
+
+func TestCanonicalizeBody_ToleratesFormattingDrift(t *testing.T) {
+	a := canonicalizeBody([]byte(`{"a":1,"b":2}`))
+	b := canonicalizeBody([]byte("{\n  \"b\": 2,\n  \"a\": 1\n}\n"))
+	if a != b {
+		t.Errorf("canonicalizeBody produced different keys for equivalent JSON with different formatting:\n%q\n%q", a, b)
+	}
+}
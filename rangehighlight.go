@@ -0,0 +1,181 @@
+package gosyntect
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// sliceForLineRange slices q.Code down to the [LineStart-Context, LineEnd]
+// window (1-indexed, inclusive), returning the sliced code, the byte offset
+// of the slice's first line within the original Code (used to translate
+// ScopifiedRegion.Offset back into the original file's coordinates), and the
+// number of leading lines sliced off (sent to the server as
+// Query.LineOffset).
+func sliceForLineRange(q *Query) (code string, byteOffset int, lineOffset int) {
+	lines := splitLinesKeepEnds(q.Code)
+
+	start := q.LineStart - 1 - q.Context
+	if start < 0 {
+		start = 0
+	}
+	end := q.LineEnd
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start >= end {
+		// LineStart is past EOF, or LineEnd < LineStart: there's no valid
+		// window to highlight. Clamp to an empty slice at end rather than
+		// falling back to the whole file, which would defeat the point of
+		// requesting a narrow range in the first place.
+		start = end
+	}
+
+	for _, line := range lines[:start] {
+		byteOffset += len(line)
+	}
+	return strings.Join(lines[start:end], ""), byteOffset, start
+}
+
+// splitLinesKeepEnds splits s into lines, each retaining its trailing "\n"
+// (the final line may lack one), so that joining the result always
+// reconstructs s exactly.
+func splitLinesKeepEnds(s string) []string {
+	var lines []string
+	for len(s) > 0 {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			lines = append(lines, s[:i+1])
+			s = s[i+1:]
+		} else {
+			lines = append(lines, s)
+			break
+		}
+	}
+	return lines
+}
+
+// chunkFallbackConfig holds the parameters for the automatic chunked
+// fallback enabled by WithChunkedFallback.
+type chunkFallbackConfig struct {
+	windowLines  int
+	overlapLines int
+}
+
+// WithChunkedFallback enables an automatic fallback for Highlight: if a
+// full-file request is rejected with ErrRequestTooLarge, Highlight instead
+// splits Code into windowLines-line windows (each extended by
+// overlapLines lines of leading context, so the grammar has a chance to
+// resynchronize state across languages like Python or YAML), highlights
+// each window, and stitches the results back into a single Response
+// covering the whole file. This mirrors how HTTP Range requests let a
+// gateway serve just the slice a caller needs instead of the whole blob.
+//
+// The stitched Response.Data is a concatenation of each window's highlighted
+// HTML and is a best-effort approximation: syntax state that only becomes
+// unambiguous after a window boundary may render slightly differently than
+// a single whole-file request would have. ScopifiedRegions are translated
+// back into the original file's coordinates; their scopes come from the same
+// windowed requests, so the same caveat applies to them.
+func WithChunkedFallback(windowLines, overlapLines int) ClientOption {
+	return func(c *Client) {
+		c.chunkFallback = &chunkFallbackConfig{windowLines: windowLines, overlapLines: overlapLines}
+	}
+}
+
+// highlightChunked implements the fallback described by WithChunkedFallback.
+//
+// Each window is sent with cfg.overlapLines of leading context so the
+// grammar has a chance to resynchronize state before the content the
+// caller actually asked for, but that context is only there to prime the
+// grammar: it duplicates content already emitted by the previous window
+// (or, for the first window, content that was never requested). It is
+// trimmed back off below before stitching, so only the [start, end]
+// portion of each window ends up in the merged result.
+func (c *Client) highlightChunked(ctx context.Context, q *Query) (*Response, error) {
+	cfg := c.chunkFallback
+	fullLines := splitLinesKeepEnds(q.Code)
+	totalLines := len(fullLines)
+
+	// lineByteOffset[i] is the byte offset of the start of line i+1 (1-
+	// indexed) within the original Code.
+	lineByteOffset := make([]int, totalLines+1)
+	for i, line := range fullLines {
+		lineByteOffset[i+1] = lineByteOffset[i] + len(line)
+	}
+
+	merged := &Response{
+		ScopifiedScopeNames: map[int]string{},
+	}
+	var dataParts []string
+	scopeNameToIndex := map[string]int{}
+
+	for start := 1; start <= totalLines; start += cfg.windowLines {
+		end := start + cfg.windowLines - 1
+		if end > totalLines {
+			end = totalLines
+		}
+
+		window := *q
+		window.LineStart = start
+		window.LineEnd = end
+		window.Context = cfg.overlapLines
+
+		resp, err := c.Highlight(ctx, &window)
+		if err != nil {
+			return nil, errors.Wrap(err, "highlighting chunk")
+		}
+
+		merged.Plaintext = merged.Plaintext || resp.Plaintext
+		if merged.DetectedLanguage == "" {
+			merged.DetectedLanguage = resp.DetectedLanguage
+		}
+
+		// contextLines is how many of the leading lines in this window's
+		// response are priming context rather than requested content.
+		contextLines := start - 1
+		if contextLines > cfg.overlapLines {
+			contextLines = cfg.overlapLines
+		}
+
+		if q.Scopify {
+			remap := make(map[int]int, len(resp.ScopifiedScopeNames))
+			for index, name := range resp.ScopifiedScopeNames {
+				newIndex, ok := scopeNameToIndex[name]
+				if !ok {
+					newIndex = len(scopeNameToIndex)
+					scopeNameToIndex[name] = newIndex
+					merged.ScopifiedScopeNames[newIndex] = name
+				}
+				remap[index] = newIndex
+			}
+			// Offsets are already translated into the original file's
+			// coordinates by Highlight, so anything before where this
+			// window's requested content starts is overlap context
+			// duplicating a region already added by the previous window.
+			cutoff := lineByteOffset[start-1]
+			for _, region := range resp.ScopifiedRegions {
+				if region.Offset < cutoff {
+					continue
+				}
+				scopes := make([]int, len(region.Scopes))
+				for i, s := range region.Scopes {
+					scopes[i] = remap[s]
+				}
+				merged.ScopifiedRegions = append(merged.ScopifiedRegions, ScopifiedRegion{
+					Offset: region.Offset,
+					Length: region.Length,
+					Scopes: scopes,
+				})
+			}
+		} else {
+			dataLines := splitLinesKeepEnds(resp.Data)
+			if contextLines > len(dataLines) {
+				contextLines = len(dataLines)
+			}
+			dataParts = append(dataParts, strings.Join(dataLines[contextLines:], ""))
+		}
+	}
+	merged.Data = strings.Join(dataParts, "")
+	return merged, nil
+}
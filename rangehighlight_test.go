@@ -0,0 +1,143 @@
+package gosyntect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSliceForLineRange(t *testing.T) {
+	code := "line1\nline2\nline3\nline4\nline5\nline6\n"
+	tests := []struct {
+		name                        string
+		lineStart, lineEnd, context int
+		wantCode                    string
+		wantByteOffset              int
+		wantLineOffset              int
+	}{
+		{"whole file via explicit range", 1, 6, 0, code, 0, 0},
+		{"middle window, no context", 3, 4, 0, "line3\nline4\n", 12, 2},
+		{"context clamped at start of file", 1, 2, 5, "line1\nline2\n", 0, 0},
+		{"middle window with context", 4, 5, 1, "line3\nline4\nline5\n", 12, 2},
+		{"LineStart past EOF", 100, 0, 0, "", len(code), 6},
+		{"LineEnd before LineStart", 5, 2, 0, "", len("line1\nline2\n"), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Query{Code: code, LineStart: tt.lineStart, LineEnd: tt.lineEnd, Context: tt.context}
+			gotCode, gotByteOffset, gotLineOffset := sliceForLineRange(q)
+			if gotCode != tt.wantCode {
+				t.Errorf("code = %q, want %q", gotCode, tt.wantCode)
+			}
+			if gotByteOffset != tt.wantByteOffset {
+				t.Errorf("byteOffset = %d, want %d", gotByteOffset, tt.wantByteOffset)
+			}
+			if gotLineOffset != tt.wantLineOffset {
+				t.Errorf("lineOffset = %d, want %d", gotLineOffset, tt.wantLineOffset)
+			}
+		})
+	}
+}
+
+func TestClient_Highlight_LineRange(t *testing.T) {
+	code := "line1\nline2\nline3\nline4\nline5\nline6\n"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q Query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatal(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response{
+			ScopifiedScopeNames: []string{"source"},
+			ScopifiedRegions:    []ScopifiedRegion{{Offset: 0, Length: len(q.Code), Scopes: []int{0}}},
+		})
+	}))
+	defer srv.Close()
+
+	cl := New(srv.URL)
+	resp, err := cl.Highlight(context.Background(), &Query{
+		Code:      code,
+		Scopify:   true,
+		LineStart: 4,
+		LineEnd:   5,
+		Context:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ScopifiedRegions) != 1 {
+		t.Fatalf("expected 1 region, got %d", len(resp.ScopifiedRegions))
+	}
+	// The server highlighted "line3\nline4\nline5\n" (line 4's window plus 1
+	// line of leading context) as a single region starting at its own
+	// offset 0; Highlight must translate that back to where line 3 actually
+	// starts in the original file.
+	wantOffset := len("line1\nline2\n")
+	if got := resp.ScopifiedRegions[0].Offset; got != wantOffset {
+		t.Errorf("ScopifiedRegions[0].Offset = %d, want %d", got, wantOffset)
+	}
+}
+
+// TestHighlightChunkedFallback reproduces the scenario from the chunked
+// fallback review: a file too large to highlight whole is split into
+// overlapping windows, and the stitched result must neither duplicate nor
+// drop the overlap region.
+func TestHighlightChunkedFallback(t *testing.T) {
+	code := "line1\nline2\nline3\nline4\nline5\nline6\n"
+	const maxSize = 20 // smaller than len(code)=36, bigger than any one window
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var q Query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Fatal(err)
+		}
+		if len(q.Code) > maxSize {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if q.Scopify {
+			lines := splitLinesKeepEnds(q.Code)
+			var regions []ScopifiedRegion
+			offset := 0
+			for _, l := range lines {
+				regions = append(regions, ScopifiedRegion{Offset: offset, Length: len(l), Scopes: []int{0}})
+				offset += len(l)
+			}
+			json.NewEncoder(w).Encode(response{ScopifiedScopeNames: []string{"source"}, ScopifiedRegions: regions})
+			return
+		}
+		json.NewEncoder(w).Encode(response{Data: q.Code})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	t.Run("plain HTML", func(t *testing.T) {
+		cl := New(srv.URL, WithChunkedFallback(2, 1))
+		resp, err := cl.Highlight(context.Background(), &Query{Code: code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Data != code {
+			t.Errorf("stitched Data = %q, want %q (overlap must be trimmed, not duplicated or dropped)", resp.Data, code)
+		}
+	})
+
+	t.Run("scopify", func(t *testing.T) {
+		cl := New(srv.URL, WithChunkedFallback(2, 1))
+		resp, err := cl.Highlight(context.Background(), &Query{Code: code, Scopify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var total int
+		for _, region := range resp.ScopifiedRegions {
+			total += region.Length
+		}
+		if total != len(code) {
+			t.Errorf("summed ScopifiedRegion.Length = %d, want %d (original file size; overlap must not be double-counted)", total, len(code))
+		}
+	})
+}